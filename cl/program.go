@@ -6,16 +6,68 @@ import "C"
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"unsafe"
 )
 
-type BuildError string
+// BuildError reports that clBuildProgram, CompileProgram or LinkProgram
+// failed, carrying a structured, per-device breakdown of the build log
+// alongside the human-readable form returned by Error().
+type BuildError struct {
+	Status  error
+	Devices []DeviceBuildLog
+}
+
+// DeviceBuildLog is one device's entry in a BuildError. Status is the
+// parsed CL_PROGRAM_BUILD_STATUS (nil on CL_BUILD_SUCCESS) — callers can
+// branch on it to tell a real compile failure apart from other problems.
+// QueryErr is set when querying the log or status itself failed (e.g. an
+// unrelated clGetProgramBuildInfo error). If the log query failed, Status,
+// Options, Log and Diagnostics are unset; if only the status query failed,
+// Status is unset and Options, Log and Diagnostics are still filled in.
+type DeviceBuildLog struct {
+	Device      string
+	Status      error
+	QueryErr    error
+	Options     string
+	Log         string
+	Diagnostics []Diagnostic
+}
+
+// Diagnostic is a single "file:line:col: error|warning: message" line
+// parsed out of a device build log.
+type Diagnostic struct {
+	File     string
+	Line     int
+	Column   int
+	Severity string
+	Message  string
+}
 
 func (e BuildError) Error() string {
-	return fmt.Sprintf("cl: build error (%s)", string(e))
+	parts := make([]string, 0, len(e.Devices)+1)
+	parts = append(parts, fmt.Sprintf("status=%v", e.Status))
+	for _, dev := range e.Devices {
+		switch {
+		case dev.Log != "":
+			parts = append(parts, fmt.Sprintf("%s:\n%s", dev.Device, dev.Log))
+		case dev.QueryErr != nil:
+			parts = append(parts, fmt.Sprintf("%s: %v", dev.Device, dev.QueryErr))
+		case dev.Status != nil:
+			parts = append(parts, fmt.Sprintf("%s: %v", dev.Device, dev.Status))
+		}
+	}
+	return fmt.Sprintf("cl: build error (%s)", strings.Join(parts, "\n"))
 }
 
 type Program struct {
@@ -64,6 +116,56 @@ func (p *Program) BuildProgram(devices []*Device, options string) error {
 	return nil
 }
 
+// Binaries returns the compiled binary for each device the program has been
+// built against, as reported by CL_PROGRAM_BINARY_SIZES/CL_PROGRAM_BINARIES.
+// It is only meaningful after a successful BuildProgram.
+func (p *Program) Binaries() (map[*Device][]byte, error) {
+	if p == nil || p.clProgram == nil {
+		return nil, ErrInvalidProgram
+	}
+	devices := p.devices
+	if len(devices) == 0 {
+		var err error
+		devices, err = p.associatedDevices()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(devices) == 0 {
+		return nil, nil
+	}
+
+	sizes := make([]C.size_t, len(devices))
+	if errCode := C.clGetProgramInfo(p.clProgram, C.CL_PROGRAM_BINARY_SIZES, C.size_t(len(sizes))*C.size_t(unsafe.Sizeof(sizes[0])), unsafe.Pointer(&sizes[0]), nil); errCode != C.CL_SUCCESS {
+		return nil, toError(errCode)
+	}
+
+	buffers := make([][]byte, len(devices))
+	// The pointer table passed to clGetProgramInfo must itself live in C
+	// memory: a Go slice of Go pointers (ptrs[i] pointing into buffers[i])
+	// is a Go pointer to Go pointers, which cgo's pointer checks reject.
+	ptrElemSize := unsafe.Sizeof((*C.uchar)(nil))
+	rawPtrs := C.malloc(C.size_t(len(devices)) * C.size_t(ptrElemSize))
+	defer C.free(rawPtrs)
+	ptrs := unsafe.Slice((**C.uchar)(rawPtrs), len(devices))
+	for i, size := range sizes {
+		if size == 0 {
+			continue
+		}
+		buffers[i] = make([]byte, int(size))
+		ptrs[i] = (*C.uchar)(unsafe.Pointer(&buffers[i][0]))
+	}
+	if errCode := C.clGetProgramInfo(p.clProgram, C.CL_PROGRAM_BINARIES, C.size_t(len(devices))*C.size_t(ptrElemSize), rawPtrs, nil); errCode != C.CL_SUCCESS {
+		return nil, toError(errCode)
+	}
+
+	result := make(map[*Device][]byte, len(devices))
+	for i, dev := range devices {
+		result[dev] = buffers[i]
+	}
+	return result, nil
+}
+
 func (p *Program) CreateKernel(name string) (*Kernel, error) {
 	cName := C.CString(name)
 	defer C.free(unsafe.Pointer(cName))
@@ -110,12 +212,17 @@ func (p *Program) wrapBuildError(code C.cl_int, requested []*Device) error {
 		return fmt.Errorf("cl: build error (%v; log unavailable: %v)", toError(code), err)
 	}
 	logs := p.collectBuildLogs(devices)
-	if len(logs) == 0 {
+	hasContent := false
+	for _, log := range logs {
+		if log.Log != "" || log.Status != nil || log.QueryErr != nil {
+			hasContent = true
+			break
+		}
+	}
+	if !hasContent {
 		return toError(code)
 	}
-	status := fmt.Sprintf("status=%v", toError(code))
-	logs = append([]string{status}, logs...)
-	return BuildError(strings.Join(logs, "\n"))
+	return BuildError{Status: toError(code), Devices: logs}
 }
 
 func (p *Program) collectDevicesForLogs(requested []*Device) ([]*Device, error) {
@@ -146,22 +253,106 @@ func (p *Program) collectDevicesForLogs(requested []*Device) ([]*Device, error)
 	return list, nil
 }
 
-func (p *Program) collectBuildLogs(devices []*Device) []string {
-	logs := make([]string, 0, len(devices))
+func (p *Program) collectBuildLogs(devices []*Device) []DeviceBuildLog {
+	logs := make([]DeviceBuildLog, 0, len(devices))
 	for _, dev := range devices {
 		label := safeDeviceLabel(dev)
 		log, err := p.GetBuildLog(dev)
 		if err != nil {
-			logs = append(logs, fmt.Sprintf("%s: <unable to fetch build log: %v>", label, err))
+			logs = append(logs, DeviceBuildLog{Device: label, QueryErr: err})
 			continue
 		}
 		log = strings.TrimSpace(log)
-		if log == "" {
+		status, queryErr := p.deviceBuildStatus(dev)
+		logs = append(logs, DeviceBuildLog{
+			Device:      label,
+			Status:      status,
+			QueryErr:    queryErr,
+			Options:     p.deviceBuildOptions(dev),
+			Log:         log,
+			Diagnostics: parseDiagnostics(log),
+		})
+	}
+	return logs
+}
+
+// deviceBuildStatus returns the parsed CL_PROGRAM_BUILD_STATUS as status
+// (nil on CL_BUILD_SUCCESS). queryErr is set instead if the
+// clGetProgramBuildInfo call itself failed, which is unrelated to whether
+// the build succeeded.
+func (p *Program) deviceBuildStatus(dev *Device) (status error, queryErr error) {
+	if dev == nil {
+		return nil, ErrInvalidDevice
+	}
+	var clStatus C.cl_build_status
+	if errCode := C.clGetProgramBuildInfo(p.clProgram, dev.id, C.CL_PROGRAM_BUILD_STATUS, C.size_t(unsafe.Sizeof(clStatus)), unsafe.Pointer(&clStatus), nil); errCode != C.CL_SUCCESS {
+		return nil, toError(errCode)
+	}
+	return buildStatusError(clStatus), nil
+}
+
+func buildStatusError(status C.cl_build_status) error {
+	switch status {
+	case C.CL_BUILD_SUCCESS:
+		return nil
+	case C.CL_BUILD_NONE:
+		return errors.New("cl: build status: none")
+	case C.CL_BUILD_IN_PROGRESS:
+		return errors.New("cl: build status: in progress")
+	case C.CL_BUILD_ERROR:
+		return errors.New("cl: build status: error")
+	default:
+		return fmt.Errorf("cl: build status: unknown (%d)", int(status))
+	}
+}
+
+func (p *Program) deviceBuildOptions(dev *Device) string {
+	if dev == nil {
+		return ""
+	}
+	var size C.size_t
+	if errCode := C.clGetProgramBuildInfo(p.clProgram, dev.id, C.CL_PROGRAM_BUILD_OPTIONS, 0, nil, &size); errCode != C.CL_SUCCESS || size == 0 {
+		return ""
+	}
+	buf := make([]byte, int(size))
+	if errCode := C.clGetProgramBuildInfo(p.clProgram, dev.id, C.CL_PROGRAM_BUILD_OPTIONS, size, unsafe.Pointer(&buf[0]), nil); errCode != C.CL_SUCCESS {
+		return ""
+	}
+	if idx := bytes.IndexByte(buf, 0); idx >= 0 {
+		buf = buf[:idx]
+	}
+	return string(buf)
+}
+
+// diagnosticPattern matches the "file:line:col: error|warning: message"
+// form most OpenCL C compilers (and clang-derived ones in particular) use
+// in CL_PROGRAM_BUILD_LOG.
+var diagnosticPattern = regexp.MustCompile(`^(.+):(\d+):(\d+):\s+(error|warning):\s+(.*)$`)
+
+func parseDiagnostics(log string) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, line := range strings.Split(log, "\n") {
+		m := diagnosticPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
 			continue
 		}
-		logs = append(logs, fmt.Sprintf("%s:\n%s", label, log))
+		lineNo, err := strconv.Atoi(m[2])
+		if err != nil {
+			continue
+		}
+		col, err := strconv.Atoi(m[3])
+		if err != nil {
+			continue
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			File:     m[1],
+			Line:     lineNo,
+			Column:   col,
+			Severity: m[4],
+			Message:  m[5],
+		})
 	}
-	return logs
+	return diagnostics
 }
 
 func (p *Program) associatedDevices() ([]*Device, error) {
@@ -205,6 +396,162 @@ func deviceIDKey(d *Device) uintptr {
 	return uintptr(unsafe.Pointer(d.id))
 }
 
+// CreateProgramWithBinary creates a program from a previously compiled
+// binary for each of devices, wrapping clCreateProgramWithBinary. The
+// binaries slice must have exactly one entry per device, typically
+// obtained from a prior call to (*Program).Binaries on a program built
+// for the same devices.
+func (ctx *Context) CreateProgramWithBinary(devices []*Device, binaries [][]byte) (*Program, error) {
+	if len(devices) == 0 {
+		return nil, ErrInvalidDevice
+	}
+	if len(devices) != len(binaries) {
+		return nil, fmt.Errorf("cl: CreateProgramWithBinary: %d devices but %d binaries", len(devices), len(binaries))
+	}
+
+	deviceList := buildDeviceIdList(devices)
+	lengths := make([]C.size_t, len(binaries))
+	// As in Binaries, the binary pointer table must live in C memory: a Go
+	// slice of Go pointers (ptrs[i] pointing into the caller's bin slices)
+	// is a Go pointer to Go pointers, which cgo's pointer checks reject.
+	ptrElemSize := unsafe.Sizeof((*C.uchar)(nil))
+	rawPtrs := C.malloc(C.size_t(len(binaries)) * C.size_t(ptrElemSize))
+	defer C.free(rawPtrs)
+	ptrs := unsafe.Slice((**C.uchar)(rawPtrs), len(binaries))
+	for i, bin := range binaries {
+		lengths[i] = C.size_t(len(bin))
+		if len(bin) > 0 {
+			ptrs[i] = (*C.uchar)(unsafe.Pointer(&bin[0]))
+		}
+	}
+	binaryStatus := make([]C.cl_int, len(binaries))
+
+	var err C.cl_int
+	clProgram := C.clCreateProgramWithBinary(ctx.clContext, C.cl_uint(len(devices)), &deviceList[0], &lengths[0], (**C.uchar)(rawPtrs), &binaryStatus[0], &err)
+	if err != C.CL_SUCCESS {
+		return nil, toError(err)
+	}
+	for i, status := range binaryStatus {
+		if status != C.CL_SUCCESS {
+			C.clReleaseProgram(clProgram)
+			return nil, fmt.Errorf("cl: CreateProgramWithBinary: device %d: %v", i, toError(status))
+		}
+	}
+
+	program := &Program{clProgram: clProgram, devices: append([]*Device(nil), devices...)}
+	runtime.SetFinalizer(program, releaseProgram)
+	return program, nil
+}
+
+// BuildProgramCached builds source for devices, caching the resulting
+// binaries under cacheDir so that later calls with identical source,
+// options, device name and driver/platform versions can load the binary
+// directly instead of paying for clBuildProgram again.
+func (ctx *Context) BuildProgramCached(devices []*Device, source, options, cacheDir string) (*Program, error) {
+	if len(devices) == 0 {
+		return nil, ErrInvalidDevice
+	}
+
+	keys := make([]string, len(devices))
+	for i, dev := range devices {
+		keys[i] = programCacheKey(source, options, dev)
+	}
+
+	if program, err := loadCachedProgram(ctx, devices, keys, cacheDir); err == nil && program != nil {
+		return program, nil
+	}
+
+	program, err := ctx.CreateProgramWithSource([]string{source})
+	if err != nil {
+		return nil, err
+	}
+	if err := program.BuildProgram(devices, options); err != nil {
+		program.Release()
+		return nil, err
+	}
+	if cacheDir != "" {
+		saveCachedProgram(program, devices, keys, cacheDir)
+	}
+	return program, nil
+}
+
+// programCacheKey derives a stable cache key from the inputs that affect
+// what clBuildProgram would produce for dev: the source, the build
+// options, the device name, and the driver/platform versions, since a cached
+// binary is only valid for the exact combination it was built with.
+func programCacheKey(source, options string, dev *Device) string {
+	h := sha256.New()
+	io.WriteString(h, source)
+	h.Write([]byte{0})
+	io.WriteString(h, options)
+	h.Write([]byte{0})
+	io.WriteString(h, dev.Name())
+	h.Write([]byte{0})
+	io.WriteString(h, dev.DriverVersion())
+	h.Write([]byte{0})
+	io.WriteString(h, dev.Platform().Version())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func cacheFilePath(cacheDir, key string) string {
+	return filepath.Join(cacheDir, key+".bin")
+}
+
+func loadCachedProgram(ctx *Context, devices []*Device, keys []string, cacheDir string) (*Program, error) {
+	if cacheDir == "" {
+		return nil, nil
+	}
+	binaries := make([][]byte, len(devices))
+	for i, key := range keys {
+		data, err := os.ReadFile(cacheFilePath(cacheDir, key))
+		if err != nil {
+			return nil, nil
+		}
+		binaries[i] = data
+	}
+	return ctx.CreateProgramWithBinary(devices, binaries)
+}
+
+func saveCachedProgram(program *Program, devices []*Device, keys []string, cacheDir string) {
+	binaries, err := program.Binaries()
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return
+	}
+	for i, dev := range devices {
+		bin, ok := binaries[dev]
+		if !ok || len(bin) == 0 {
+			continue
+		}
+		writeCacheFileAtomic(cacheDir, cacheFilePath(cacheDir, keys[i]), bin)
+	}
+}
+
+// writeCacheFileAtomic writes data to path via a temp file plus rename, so
+// that a concurrent loadCachedProgram (e.g. another goroutine compiling the
+// same kernel against the same cacheDir) never observes a partial write.
+func writeCacheFileAtomic(cacheDir, path string, data []byte) {
+	tmp, err := os.CreateTemp(cacheDir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+	}
+}
+
 func safeDeviceLabel(d *Device) string {
 	if d == nil {
 		return "device<nil>"
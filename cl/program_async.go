@@ -0,0 +1,161 @@
+package cl
+
+// #include <stdlib.h>
+// #include "cl.h"
+//
+// extern void goProgramBuildCallback(cl_program program, void *user_data);
+import "C"
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// buildCallbackSeq hands out the ids used as pfn_notify user_data, since we
+// cannot pass a Go pointer through C across the lifetime of an async build.
+var buildCallbackSeq uint64
+
+var (
+	buildCallbackMu    sync.Mutex
+	buildCallbackState = make(map[uint64]*buildAsyncState)
+)
+
+type buildAsyncState struct {
+	program *Program
+	devices []*Device
+	done    chan error
+}
+
+// BuildProgramAsync builds the program for devices without blocking the
+// calling goroutine on clBuildProgram. Completion is signalled through the
+// pfn_notify callback slot (passed as nil by BuildProgram), trampolined into
+// Go via goProgramBuildCallback. If ctx is cancelled before the build
+// finishes, the returned channel receives ctx.Err() immediately and this
+// build's registry entry is detached, so a callback that arrives afterward
+// finds nothing registered and is a no-op; the build itself still runs to
+// completion on the driver side regardless; only the Go-side bookkeeping is
+// released early, not the underlying clBuildProgram call.
+func (p *Program) BuildProgramAsync(ctx context.Context, devices []*Device, options string) <-chan error {
+	result := make(chan error, 1)
+
+	var cOptions *C.char
+	if options != "" {
+		cOptions = C.CString(options)
+	}
+	var deviceList []C.cl_device_id
+	var deviceListPtr *C.cl_device_id
+	numDevices := C.cl_uint(len(devices))
+	if len(devices) > 0 {
+		deviceList = buildDeviceIdList(devices)
+		deviceListPtr = &deviceList[0]
+	}
+
+	id := atomic.AddUint64(&buildCallbackSeq, 1)
+	state := &buildAsyncState{program: p, devices: append([]*Device(nil), devices...), done: make(chan error, 1)}
+	buildCallbackMu.Lock()
+	buildCallbackState[id] = state
+	buildCallbackMu.Unlock()
+
+	userData := C.malloc(C.size_t(unsafe.Sizeof(C.uint64_t(0))))
+	*(*C.uint64_t)(userData) = C.uint64_t(id)
+
+	errCode := C.clBuildProgram(p.clProgram, numDevices, deviceListPtr, cOptions, (*[0]byte)(C.goProgramBuildCallback), userData)
+	if cOptions != nil {
+		C.free(unsafe.Pointer(cOptions))
+	}
+	if errCode != C.CL_SUCCESS {
+		buildCallbackMu.Lock()
+		delete(buildCallbackState, id)
+		buildCallbackMu.Unlock()
+		C.free(userData)
+		if buildErr := p.wrapBuildError(errCode, devices); buildErr != nil {
+			result <- buildErr
+		} else {
+			result <- toError(errCode)
+		}
+		close(result)
+		return result
+	}
+
+	go func() {
+		defer close(result)
+		select {
+		case err := <-state.done:
+			if err == nil {
+				if len(devices) > 0 {
+					p.devices = append([]*Device(nil), devices...)
+				} else if len(p.devices) == 0 {
+					if progDevices, err := p.associatedDevices(); err == nil && len(progDevices) > 0 {
+						p.devices = progDevices
+					}
+				}
+			}
+			result <- err
+		case <-ctx.Done():
+			detachBuildCallback(id)
+			result <- ctx.Err()
+		}
+	}()
+
+	return result
+}
+
+// detachBuildCallback removes id's entry from the registry without waiting
+// for goProgramBuildCallback to do it, so a cancelled BuildProgramAsync's
+// state (and the devices it holds) can be collected immediately instead of
+// living until the driver eventually calls back. The callback itself still
+// frees its C-allocated user_data unconditionally, so this never leaks C
+// memory; it only makes an eventual late callback a no-op.
+func detachBuildCallback(id uint64) {
+	buildCallbackMu.Lock()
+	delete(buildCallbackState, id)
+	buildCallbackMu.Unlock()
+}
+
+//export goProgramBuildCallback
+func goProgramBuildCallback(program C.cl_program, userData unsafe.Pointer) {
+	id := *(*C.uint64_t)(userData)
+	C.free(userData)
+
+	buildCallbackMu.Lock()
+	state, ok := buildCallbackState[uint64(id)]
+	if ok {
+		delete(buildCallbackState, uint64(id))
+	}
+	buildCallbackMu.Unlock()
+	if !ok {
+		return
+	}
+
+	state.done <- state.program.checkBuildStatus(state.devices)
+}
+
+// checkBuildStatus queries CL_PROGRAM_BUILD_STATUS for each device and, if
+// any of them failed, returns a BuildError built from the same per-device
+// logs BuildProgram's synchronous path reports.
+func (p *Program) checkBuildStatus(devices []*Device) error {
+	if len(devices) == 0 {
+		var err error
+		devices, err = p.associatedDevices()
+		if err != nil {
+			return err
+		}
+	}
+
+	var failed bool
+	for _, dev := range devices {
+		status, queryErr := p.deviceBuildStatus(dev)
+		if status != nil || queryErr != nil {
+			failed = true
+			break
+		}
+	}
+	if !failed {
+		return nil
+	}
+
+	return BuildError{Status: errors.New("cl: build status: error"), Devices: p.collectBuildLogs(devices)}
+}
@@ -0,0 +1,112 @@
+package cl
+
+// #include <stdlib.h>
+// #include "cl.h"
+import "C"
+
+import (
+	"runtime"
+	"unsafe"
+)
+
+// CompileProgram compiles the program's source for devices without linking
+// it, wrapping clCompileProgram. headers maps the include name a #include
+// directive in the source refers to onto the already-created Program whose
+// source should be substituted for it. Compile diagnostics are surfaced the
+// same way BuildProgram reports them.
+func (p *Program) CompileProgram(devices []*Device, options string, headers map[string]*Program) error {
+	var cOptions *C.char
+	if options != "" {
+		cOptions = C.CString(options)
+		defer C.free(unsafe.Pointer(cOptions))
+	}
+	var deviceList []C.cl_device_id
+	var deviceListPtr *C.cl_device_id
+	numDevices := C.cl_uint(len(devices))
+	if len(devices) > 0 {
+		deviceList = buildDeviceIdList(devices)
+		deviceListPtr = &deviceList[0]
+	}
+
+	var headerPrograms []C.cl_program
+	var headerNames []*C.char
+	if len(headers) > 0 {
+		headerPrograms = make([]C.cl_program, 0, len(headers))
+		headerNames = make([]*C.char, 0, len(headers))
+		for name, hp := range headers {
+			headerPrograms = append(headerPrograms, hp.clProgram)
+			headerNames = append(headerNames, C.CString(name))
+		}
+		defer func() {
+			for _, cName := range headerNames {
+				C.free(unsafe.Pointer(cName))
+			}
+		}()
+	}
+	var headerProgramsPtr *C.cl_program
+	var headerNamePtr **C.char
+	if len(headerPrograms) > 0 {
+		headerProgramsPtr = &headerPrograms[0]
+		headerNamePtr = &headerNames[0]
+	}
+
+	errCode := C.clCompileProgram(p.clProgram, numDevices, deviceListPtr, cOptions, C.cl_uint(len(headerPrograms)), headerProgramsPtr, headerNamePtr, nil, nil)
+	if errCode != C.CL_SUCCESS {
+		if buildErr := p.wrapBuildError(errCode, devices); buildErr != nil {
+			return buildErr
+		}
+		return toError(errCode)
+	}
+	if len(devices) > 0 {
+		p.devices = append([]*Device(nil), devices...)
+	}
+	return nil
+}
+
+// LinkProgram links one or more separately compiled programs into a single
+// executable program, wrapping clLinkProgram. Link diagnostics are routed
+// through the same per-device build log machinery CompileProgram and
+// BuildProgram use.
+func LinkProgram(ctx *Context, devices []*Device, options string, inputs []*Program) (*Program, error) {
+	var cOptions *C.char
+	if options != "" {
+		cOptions = C.CString(options)
+		defer C.free(unsafe.Pointer(cOptions))
+	}
+	var deviceList []C.cl_device_id
+	var deviceListPtr *C.cl_device_id
+	numDevices := C.cl_uint(len(devices))
+	if len(devices) > 0 {
+		deviceList = buildDeviceIdList(devices)
+		deviceListPtr = &deviceList[0]
+	}
+
+	inputPrograms := make([]C.cl_program, len(inputs))
+	for i, in := range inputs {
+		inputPrograms[i] = in.clProgram
+	}
+	var inputProgramsPtr *C.cl_program
+	if len(inputPrograms) > 0 {
+		inputProgramsPtr = &inputPrograms[0]
+	}
+
+	var errCode C.cl_int
+	clProgram := C.clLinkProgram(ctx.clContext, numDevices, deviceListPtr, cOptions, C.cl_uint(len(inputPrograms)), inputProgramsPtr, nil, nil, &errCode)
+	if errCode != C.CL_SUCCESS {
+		if clProgram != nil {
+			linked := &Program{clProgram: clProgram}
+			defer linked.Release()
+			if buildErr := linked.wrapBuildError(errCode, devices); buildErr != nil {
+				return nil, buildErr
+			}
+		}
+		return nil, toError(errCode)
+	}
+
+	program := &Program{clProgram: clProgram}
+	if len(devices) > 0 {
+		program.devices = append([]*Device(nil), devices...)
+	}
+	runtime.SetFinalizer(program, releaseProgram)
+	return program, nil
+}
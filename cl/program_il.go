@@ -0,0 +1,152 @@
+package cl
+
+// #include <stdlib.h>
+// #include "cl.h"
+//
+// #ifndef CL_VERSION_2_1
+// #define CL_GO_NO_CREATE_PROGRAM_WITH_IL 1
+// #endif
+//
+// #ifndef CL_DEVICE_IL_VERSION
+// #define CL_DEVICE_IL_VERSION 0x105B
+// #endif
+// #ifndef CL_PROGRAM_IL
+// #define CL_PROGRAM_IL 0x1169
+// #endif
+//
+// static cl_program cl_go_create_program_with_il(cl_context ctx, const void *il, size_t length, cl_int *errcode_ret) {
+// #ifdef CL_GO_NO_CREATE_PROGRAM_WITH_IL
+//     *errcode_ret = CL_INVALID_OPERATION;
+//     return NULL;
+// #else
+//     return clCreateProgramWithIL(ctx, il, length, errcode_ret);
+// #endif
+// }
+//
+// typedef cl_program (CL_API_CALL *cl_go_CreateProgramWithILKHR_fn)(cl_context, const void *, size_t, cl_int *);
+//
+// static cl_program cl_go_call_create_program_with_il_khr(void *fn, cl_context ctx, const void *il, size_t length, cl_int *errcode_ret) {
+//     cl_go_CreateProgramWithILKHR_fn f = (cl_go_CreateProgramWithILKHR_fn)fn;
+//     return f(ctx, il, length, errcode_ret);
+// }
+import "C"
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"unsafe"
+)
+
+// CreateProgramWithIL creates a program from a SPIR-V (or other
+// implementation-defined IL) module, wrapping clCreateProgramWithIL
+// (OpenCL 2.1). On platforms whose headers or ICD predate 2.1, it falls
+// back to the cl_khr_il_program extension's clCreateProgramWithILKHR,
+// resolved at runtime via clGetExtensionFunctionAddressForPlatform.
+func (ctx *Context) CreateProgramWithIL(il []byte) (*Program, error) {
+	if len(il) == 0 {
+		return nil, fmt.Errorf("cl: CreateProgramWithIL: empty il")
+	}
+
+	var errCode C.cl_int
+	clProgram := C.cl_go_create_program_with_il(ctx.clContext, unsafe.Pointer(&il[0]), C.size_t(len(il)), &errCode)
+	if errCode == C.CL_INVALID_OPERATION && clProgram == nil {
+		var err error
+		clProgram, err = createProgramWithILKHR(ctx.clContext, il)
+		if err != nil {
+			return nil, err
+		}
+	} else if errCode != C.CL_SUCCESS {
+		return nil, toError(errCode)
+	}
+
+	program := &Program{clProgram: clProgram}
+	runtime.SetFinalizer(program, releaseProgram)
+	return program, nil
+}
+
+func createProgramWithILKHR(clContext C.cl_context, il []byte) (C.cl_program, error) {
+	platform, err := contextPlatform(clContext)
+	if err != nil {
+		return nil, err
+	}
+
+	cName := C.CString("clCreateProgramWithILKHR")
+	defer C.free(unsafe.Pointer(cName))
+	fnPtr := C.clGetExtensionFunctionAddressForPlatform(platform, cName)
+	if fnPtr == nil {
+		return nil, fmt.Errorf("cl: CreateProgramWithIL: clCreateProgramWithIL is unavailable and the platform does not support cl_khr_il_program")
+	}
+
+	var errCode C.cl_int
+	clProgram := C.cl_go_call_create_program_with_il_khr(fnPtr, clContext, unsafe.Pointer(&il[0]), C.size_t(len(il)), &errCode)
+	if errCode != C.CL_SUCCESS {
+		return nil, toError(errCode)
+	}
+	return clProgram, nil
+}
+
+// contextPlatform looks up the platform a context's devices belong to, so
+// that extension entry points such as clCreateProgramWithILKHR can be
+// resolved without requiring the caller to pass a platform explicitly.
+func contextPlatform(clContext C.cl_context) (C.cl_platform_id, error) {
+	var size C.size_t
+	if errCode := C.clGetContextInfo(clContext, C.CL_CONTEXT_DEVICES, 0, nil, &size); errCode != C.CL_SUCCESS {
+		return nil, toError(errCode)
+	}
+	var sample C.cl_device_id
+	elemSize := C.size_t(unsafe.Sizeof(sample))
+	if size == 0 || elemSize == 0 {
+		return nil, ErrInvalidDevice
+	}
+	ids := make([]C.cl_device_id, int(size/elemSize))
+	if errCode := C.clGetContextInfo(clContext, C.CL_CONTEXT_DEVICES, size, unsafe.Pointer(&ids[0]), nil); errCode != C.CL_SUCCESS {
+		return nil, toError(errCode)
+	}
+
+	var platform C.cl_platform_id
+	if errCode := C.clGetDeviceInfo(ids[0], C.CL_DEVICE_PLATFORM, C.size_t(unsafe.Sizeof(platform)), unsafe.Pointer(&platform), nil); errCode != C.CL_SUCCESS {
+		return nil, toError(errCode)
+	}
+	return platform, nil
+}
+
+// ILVersion returns the device's CL_DEVICE_IL_VERSION string (e.g.
+// "SPIR-V_1.0"), or "" if the device or its driver doesn't report one.
+func (d *Device) ILVersion() string {
+	if d == nil || d.id == nil {
+		return ""
+	}
+	var size C.size_t
+	if errCode := C.clGetDeviceInfo(d.id, C.CL_DEVICE_IL_VERSION, 0, nil, &size); errCode != C.CL_SUCCESS || size == 0 {
+		return ""
+	}
+	buf := make([]byte, int(size))
+	if errCode := C.clGetDeviceInfo(d.id, C.CL_DEVICE_IL_VERSION, size, unsafe.Pointer(&buf[0]), nil); errCode != C.CL_SUCCESS {
+		return ""
+	}
+	if idx := bytes.IndexByte(buf, 0); idx >= 0 {
+		buf = buf[:idx]
+	}
+	return string(buf)
+}
+
+// IL returns the program's intermediate language module, wrapping
+// CL_PROGRAM_IL. It returns nil if the program wasn't created from IL.
+func (p *Program) IL() ([]byte, error) {
+	if p == nil || p.clProgram == nil {
+		return nil, ErrInvalidProgram
+	}
+	var size C.size_t
+	if errCode := C.clGetProgramInfo(p.clProgram, C.CL_PROGRAM_IL, 0, nil, &size); errCode != C.CL_SUCCESS {
+		return nil, toError(errCode)
+	}
+	if size == 0 {
+		return nil, nil
+	}
+	buf := make([]byte, int(size))
+	if errCode := C.clGetProgramInfo(p.clProgram, C.CL_PROGRAM_IL, size, unsafe.Pointer(&buf[0]), nil); errCode != C.CL_SUCCESS {
+		return nil, toError(errCode)
+	}
+	return buf, nil
+}